@@ -1,6 +1,16 @@
 package driver
 
-import "log"
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"github.com/hashicorp/consul-terraform-sync/templates/tftmpl"
+	goVersion "github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+)
 
 const (
 	terraformVersion = "0.13.0-beta2"
@@ -61,10 +71,102 @@ func (tf *Terraform) Init() error {
 			"already exists at path %s/terraform", tf.path)
 	}
 
+	// Resolve the actual version of the binary at path, which may differ
+	// from the version CTS would have installed (e.g. an operator-supplied
+	// binary), so Validate compares against what is really there.
+	installedVersion, err := resolveInstalledVersion(tf.path)
+	if err != nil {
+		log.Printf("[WARN] (driver.terraform) unable to resolve installed terraform "+
+			"version, falling back to %s: %s", tf.version, err)
+		return nil
+	}
+	tf.version = installedVersion
+
 	return nil
 }
 
+// terraformVersionOutputRe matches the version line of `terraform -version`
+// output, e.g. "Terraform v1.2.3".
+var terraformVersionOutputRe = regexp.MustCompile(`Terraform v(\S+)`)
+
+// resolveInstalledVersion runs the Terraform binary at path to determine
+// its actual version.
+func resolveInstalledVersion(path string) (string, error) {
+	out, err := exec.Command(filepath.Join(path, "terraform"), "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("error running terraform -version: %s", err)
+	}
+
+	match := terraformVersionOutputRe.FindSubmatch(out)
+	if match == nil {
+		return "", fmt.Errorf("unable to parse terraform version from output: %s", out)
+	}
+	return string(match[1]), nil
+}
+
 // Version returns the Terraform CLI version for the Terraform driver.
 func (tf *Terraform) Version() string {
 	return tf.version
-}
\ No newline at end of file
+}
+
+// Validate cross-checks the required_version constraint written to the
+// task's generated root module against the Terraform binary resolved by
+// Init, and confirms each required_providers entry's source address is
+// well-formed. The version constraints of required_providers entries are
+// not checked against the binary. It returns a diagnostic identifying the
+// offending file, range, and failing constraint so task startup fails
+// fast instead of deep inside `terraform init`.
+func (tf *Terraform) Validate(input *tftmpl.RootModuleInputData) error {
+	path := filepath.Join(input.Path, tftmpl.RootFilename)
+
+	requiredVersion, versionRange, diags := tftmpl.ParseRequiredVersion(path)
+	if diags.HasErrors() {
+		return fmt.Errorf("error validating root module %q: %s", path, diags)
+	}
+
+	if requiredVersion != "" {
+		constraints, err := goVersion.NewConstraint(requiredVersion)
+		if err != nil {
+			return fmt.Errorf("error parsing required_version constraint %q in %s: %s",
+				requiredVersion, path, err)
+		}
+
+		installed, err := goVersion.NewVersion(tf.version)
+		if err != nil {
+			return fmt.Errorf("error parsing installed terraform version %q: %s", tf.version, err)
+		}
+
+		if !constraints.Check(installed) {
+			rng := versionRange
+			return hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "terraform version does not satisfy required_version",
+				Detail: fmt.Sprintf("installed terraform %s does not satisfy the "+
+					"required_version constraint %q in %s", tf.version, requiredVersion, path),
+				Subject: &rng,
+			}}
+		}
+	}
+
+	providers, diags := tftmpl.ParseRequiredProviders(path)
+	if diags.HasErrors() {
+		return fmt.Errorf("error validating root module %q: %s", path, diags)
+	}
+
+	for name, p := range providers {
+		if p.Source == "" {
+			continue
+		}
+		if err := tftmpl.ValidateProviderSource(p.Source); err != nil {
+			rng := p.Range
+			return hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "invalid provider source address",
+				Detail:   fmt.Sprintf("provider %q in %s: %s", name, path, err),
+				Subject:  &rng,
+			}}
+		}
+	}
+
+	return nil
+}
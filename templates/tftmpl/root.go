@@ -1,15 +1,17 @@
 package tftmpl
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/hashicorp/consul-terraform-sync/internal/hcl2shim"
 	"github.com/hashicorp/consul-terraform-sync/templates/hcltmpl"
 	"github.com/hashicorp/consul-terraform-sync/version"
 	goVersion "github.com/hashicorp/go-version"
@@ -89,6 +91,20 @@ type Task struct {
 	Name        string
 	Source      string
 	Version     string
+
+	// InlineContent is raw HCL (resources, data sources, locals, etc.)
+	// written directly into the root module in place of a `module` block
+	// referencing Source. This lets operators write small automation tasks
+	// without packaging a separate module repo. Source and Version are
+	// ignored when InlineContent is set.
+	InlineContent string
+
+	// ConfigurationAliases are the aliased provider references (e.g.
+	// "aws.west") that the task's module declares via its
+	// configuration_aliases provider meta-argument. When set, the module
+	// block is passed a `providers` map wiring each aliased provider
+	// instance through.
+	ConfigurationAliases []string
 }
 
 type Service struct {
@@ -138,16 +154,26 @@ func (s Service) hcatQuery() string {
 	return query
 }
 
+// ProviderRequirement is a required_providers entry for a single provider
+// used by a task's root module. It is written using the Terraform 0.13+
+// structured source/version form rather than the legacy bare-string
+// constraint.
+type ProviderRequirement struct {
+	Name    string
+	Source  string
+	Version string
+}
+
 // RootModuleInputData is the input data used to generate the root module
 type RootModuleInputData struct {
-	TerraformVersion *goVersion.Version
-	Backend          map[string]interface{}
-	Providers        []hcltmpl.NamedBlock
-	ProviderInfo     map[string]interface{}
-	Services         []Service
-	Task             Task
-	Variables        hcltmpl.Variables
-	Condition        Condition
+	TerraformVersion     *goVersion.Version
+	Backend              map[string]interface{}
+	Providers            []hcltmpl.NamedBlock
+	ProviderRequirements []ProviderRequirement
+	Services             []Service
+	Task                 Task
+	Variables            hcltmpl.Variables
+	Condition            Condition
 
 	Path      string
 	FilePerms os.FileMode
@@ -173,15 +199,517 @@ func (d *RootModuleInputData) init() {
 		return d.Providers[i].Name < d.Providers[j].Name
 	})
 
+	sort.Slice(d.ProviderRequirements, func(i, j int) bool {
+		return d.ProviderRequirements[i].Name < d.ProviderRequirements[j].Name
+	})
+
 	sort.Slice(d.Services, func(i, j int) bool {
 		return d.Services[i].Name < d.Services[j].Name
 	})
 }
 
+// Changes describes the modifications UpgradeRootModule made to an
+// existing root module, so that callers can log what was migrated.
+type Changes struct {
+	// RequiredVersion is set to the new required_version constraint when it
+	// was updated, or left empty if it was already current.
+	RequiredVersion string
+
+	// AddedProviders lists provider names newly added to required_providers.
+	AddedProviders []string
+
+	// UpdatedProviders lists provider names whose source address or
+	// version constraint changed.
+	UpdatedProviders []string
+
+	// RemovedProviders lists provider names removed from required_providers
+	// because the task no longer requires them.
+	RemovedProviders []string
+
+	// BackendUpdated is true when the terraform block's backend
+	// configuration was added, removed, or changed to match input.
+	BackendUpdated bool
+
+	// ProviderBlocksUpdated is true when the set of top-level provider
+	// blocks was regenerated because the provider blocks input would
+	// generate (their instances or their arguments) no longer matched what
+	// was on disk.
+	ProviderBlocksUpdated bool
+
+	// UpdatedProviderBlocks lists the provider instance keys ("name", or
+	// "name.alias" for an aliased instance, see providerAliasKey) that the
+	// regenerated provider blocks now reflect. Empty when the last
+	// provider instance was removed.
+	UpdatedProviderBlocks []string
+
+	// AddedVariables lists variable names newly declared in variables.tf,
+	// e.g. for a provider instance that didn't previously exist.
+	AddedVariables []string
+
+	// RemovedVariables lists provider variable names removed from
+	// variables.tf because the provider instance they belonged to no
+	// longer exists.
+	RemovedVariables []string
+
+	// ProvidersConfigUpdated is true when providers.tfvars was rewritten
+	// to match the task's current provider configuration.
+	ProvidersConfigUpdated bool
+}
+
+// String returns a human readable summary of the changes for logging.
+func (c Changes) String() string {
+	var parts []string
+	if c.RequiredVersion != "" {
+		parts = append(parts, fmt.Sprintf("required_version -> %s", c.RequiredVersion))
+	}
+	if len(c.AddedProviders) > 0 {
+		parts = append(parts, fmt.Sprintf("added providers: %s", strings.Join(c.AddedProviders, ", ")))
+	}
+	if len(c.UpdatedProviders) > 0 {
+		parts = append(parts, fmt.Sprintf("updated providers: %s", strings.Join(c.UpdatedProviders, ", ")))
+	}
+	if len(c.RemovedProviders) > 0 {
+		parts = append(parts, fmt.Sprintf("removed providers: %s", strings.Join(c.RemovedProviders, ", ")))
+	}
+	if c.BackendUpdated {
+		parts = append(parts, "backend updated")
+	}
+	if c.ProviderBlocksUpdated {
+		if len(c.UpdatedProviderBlocks) > 0 {
+			parts = append(parts, fmt.Sprintf("updated provider blocks: %s", strings.Join(c.UpdatedProviderBlocks, ", ")))
+		} else {
+			parts = append(parts, "removed provider blocks")
+		}
+	}
+	if len(c.AddedVariables) > 0 {
+		parts = append(parts, fmt.Sprintf("added variables: %s", strings.Join(c.AddedVariables, ", ")))
+	}
+	if len(c.RemovedVariables) > 0 {
+		parts = append(parts, fmt.Sprintf("removed variables: %s", strings.Join(c.RemovedVariables, ", ")))
+	}
+	if c.ProvidersConfigUpdated {
+		parts = append(parts, "providers.tfvars updated")
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// UpgradeRootModule reconciles an existing generated root module on disk
+// with the module that input would now generate. Rather than
+// unconditionally overwriting main.tf, it parses the existing file with
+// hclwrite and rewrites only the terraform block's required_version,
+// required_providers, and backend configuration that differ from input,
+// preserving any other operator edits and comments. If any top-level
+// provider block differs from input, all provider blocks are removed and
+// regenerated together. Any provider variables that don't yet exist are
+// appended to variables.tf. It returns the set of changes made so callers
+// can log what was migrated. If the root module does not yet exist, it
+// falls back to InitRootModule.
+func UpgradeRootModule(input *RootModuleInputData) (Changes, error) {
+	input.init()
+
+	var changes Changes
+
+	path := filepath.Join(input.Path, RootFilename)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return changes, InitRootModule(input)
+		}
+		return changes, err
+	}
+
+	f, diags := hclwrite.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return changes, fmt.Errorf("error parsing existing root module %q: %s", path, diags)
+	}
+
+	tfBlock := f.Body().FirstMatchingBlock("terraform", nil)
+	if tfBlock == nil {
+		return changes, InitRootModule(input)
+	}
+	tfBody := tfBlock.Body()
+
+	if !attributeEqualsString(tfBody.GetAttribute("required_version"), TerraformRequiredVersion) {
+		tfBody.SetAttributeValue("required_version", cty.StringVal(TerraformRequiredVersion))
+		changes.RequiredVersion = TerraformRequiredVersion
+	}
+
+	var emptyProvidersBlockRemoved bool
+	providersBlock := tfBody.FirstMatchingBlock("required_providers", nil)
+	if providersBlock == nil && len(input.ProviderRequirements) > 0 {
+		providersBlock = tfBody.AppendNewBlock("required_providers", nil)
+	}
+	if providersBlock != nil {
+		existingConstraints, diags := ParseRequiredProviders(path)
+		if diags.HasErrors() {
+			return changes, fmt.Errorf("error reading existing required_providers for task %q: %s",
+				input.Task.Name, diags)
+		}
+
+		wantRequirements := make(map[string]bool, len(input.ProviderRequirements))
+		for _, req := range input.ProviderRequirements {
+			wantRequirements[req.Name] = true
+
+			source := req.Source
+			if source == "" {
+				source = fmt.Sprintf("hashicorp/%s", req.Name)
+			}
+
+			existing, ok := existingConstraints[req.Name]
+			switch {
+			case !ok:
+				changes.AddedProviders = append(changes.AddedProviders, req.Name)
+			case existing.Source != source || existing.Version != req.Version:
+				changes.UpdatedProviders = append(changes.UpdatedProviders, req.Name)
+			}
+		}
+
+		for name := range existingConstraints {
+			if !wantRequirements[name] {
+				providersBlock.Body().RemoveAttribute(name)
+				changes.RemovedProviders = append(changes.RemovedProviders, name)
+			}
+		}
+		sort.Strings(changes.RemovedProviders)
+
+		if diags := appendRequiredProviders(providersBlock.Body(), input.ProviderRequirements); diags.HasErrors() {
+			return changes, fmt.Errorf("error upgrading required_providers for task %q: %s",
+				input.Task.Name, diags)
+		}
+
+		if len(input.ProviderRequirements) == 0 {
+			tfBody.RemoveBlock(providersBlock)
+			emptyProvidersBlockRemoved = true
+		}
+	}
+
+	if backendBlock := tfBody.FirstMatchingBlock("backend", nil); backendChanged(backendBlock, input.backend) {
+		if backendBlock != nil {
+			tfBody.RemoveBlock(backendBlock)
+		}
+		if input.backend != nil {
+			backendBody := tfBody.AppendNewBlock("backend", []string{input.backend.Name}).Body()
+			for _, attr := range input.backend.SortedAttributes() {
+				backendBody.SetAttributeValue(attr, input.backend.Variables[attr])
+			}
+		}
+		changes.BackendUpdated = true
+	}
+
+	var existingProviderBlocks []*hclwrite.Block
+	for _, block := range f.Body().Blocks() {
+		if block.Type() == "provider" {
+			existingProviderBlocks = append(existingProviderBlocks, block)
+		}
+	}
+	if providerBlocksChanged(existingProviderBlocks, input.Providers) {
+		for _, block := range existingProviderBlocks {
+			f.Body().RemoveBlock(block)
+		}
+		appendRootProviderBlocks(f.Body(), input.Providers)
+		changes.ProviderBlocksUpdated = true
+		changes.UpdatedProviderBlocks = desiredProviderKeys(input.Providers)
+	}
+
+	mainTFChanged := changes.RequiredVersion != "" || len(changes.AddedProviders) > 0 ||
+		len(changes.UpdatedProviders) > 0 || len(changes.RemovedProviders) > 0 ||
+		changes.BackendUpdated || changes.ProviderBlocksUpdated || emptyProvidersBlockRemoved
+
+	if mainTFChanged {
+		content := hclwrite.Format(f.Bytes())
+		if err := os.WriteFile(path, content, input.FilePerms); err != nil {
+			return changes, fmt.Errorf("error writing upgraded root module %q: %s", path, err)
+		}
+	}
+
+	providersConfigUpdated, previousProviderVars, err := upgradeProvidersTFVars(input)
+	if err != nil {
+		return changes, err
+	}
+	changes.ProvidersConfigUpdated = providersConfigUpdated
+
+	added, removed, err := upgradeVariablesTF(input, previousProviderVars)
+	if err != nil {
+		return changes, err
+	}
+	changes.AddedVariables = added
+	changes.RemovedVariables = removed
+
+	if mainTFChanged || len(changes.AddedVariables) > 0 || len(changes.RemovedVariables) > 0 ||
+		changes.ProvidersConfigUpdated {
+		log.Printf("[INFO] (templates.tftmpl) upgraded root module for task %q: %s",
+			input.Task.Name, changes)
+	}
+
+	return changes, nil
+}
+
+// providerBlocksChanged reports whether the top-level provider blocks that
+// would be generated for providers differ from the provider blocks parsed
+// from the root module, either because a provider instance was added or
+// removed, or because a provider instance's generated arguments (e.g. its
+// configured attribute set) changed.
+func providerBlocksChanged(existing []*hclwrite.Block, providers []hcltmpl.NamedBlock) bool {
+	want := hclwrite.NewEmptyFile()
+	appendRootProviderBlocks(want.Body(), providers)
+	wantBlocks := want.Body().Blocks()
+
+	if len(wantBlocks) != len(existing) {
+		return true
+	}
+
+	existingByKey := make(map[string]*hclwrite.Block, len(existing))
+	for _, block := range existing {
+		existingByKey[providerBlockKey(block)] = block
+	}
+
+	for _, wantBlock := range wantBlocks {
+		existingBlock, ok := existingByKey[providerBlockKey(wantBlock)]
+		if !ok || !tokensEqual(existingBlock.BuildTokens(nil), wantBlock.BuildTokens(nil)) {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeProvidersTFVars rewrites providers.tfvars to match the task's
+// current provider configuration if it has drifted. Unlike variables.tf,
+// providers.tfvars is entirely CTS-generated (see ProvidersTFVarsFilename),
+// so it is safe to regenerate in full rather than diff incrementally. It
+// returns whether the file was rewritten, along with the provider variable
+// names it previously assigned, so the caller can tell which provider
+// variables in variables.tf (see providerVarName) no longer have a
+// provider instance backing them.
+func upgradeProvidersTFVars(input *RootModuleInputData) (bool, []string, error) {
+	path := filepath.Join(input.Path, ProvidersTFVarsFilename)
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, nil, fmt.Errorf("error reading existing %s for task %q: %s",
+			ProvidersTFVarsFilename, input.Task.Name, err)
+	}
+
+	var previousNames []string
+	if len(existing) > 0 {
+		existingFile, diags := hclwrite.ParseConfig(existing, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			log.Printf("[WARN] (templates.tftmpl) unable to parse existing %s for task %q, "+
+				"stale provider variables in %s may not be cleaned up: %s",
+				ProvidersTFVarsFilename, input.Task.Name, VarsFilename, diags)
+		} else {
+			for name := range existingFile.Body().Attributes() {
+				previousNames = append(previousNames, name)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := newProvidersTFVars(&buf, ProvidersTFVarsFilename, input); err != nil {
+		return false, previousNames, fmt.Errorf("error generating %s for task %q: %s",
+			ProvidersTFVarsFilename, input.Task.Name, err)
+	}
+
+	if bytes.Equal(existing, buf.Bytes()) {
+		return false, previousNames, nil
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), input.FilePerms); err != nil {
+		return false, previousNames, fmt.Errorf("error upgrading %s for task %q: %s",
+			ProvidersTFVarsFilename, input.Task.Name, err)
+	}
+	return true, previousNames, nil
+}
+
+// backendChanged reports whether the backend block that would be generated
+// for backend differs from the existing block parsed from the root
+// module, so UpgradeRootModule only rewrites the backend block when it is
+// actually out of date.
+func backendChanged(existing *hclwrite.Block, backend *hcltmpl.NamedBlock) bool {
+	if backend == nil {
+		return existing != nil
+	}
+	if existing == nil {
+		return true
+	}
+	if len(existing.Labels()) != 1 || existing.Labels()[0] != backend.Name {
+		return true
+	}
+
+	want := hclwrite.NewEmptyFile()
+	wantBody := want.Body().AppendNewBlock("backend", []string{backend.Name}).Body()
+	for _, attr := range backend.SortedAttributes() {
+		wantBody.SetAttributeValue(attr, backend.Variables[attr])
+	}
+
+	return !tokensEqual(existing.BuildTokens(nil), want.Body().FirstMatchingBlock("backend", nil).BuildTokens(nil))
+}
+
+// desiredProviderKeys returns the sorted set of provider instance keys
+// ("name", or "name.alias" for an aliased instance) that providers should
+// produce, reported in Changes.UpdatedProviderBlocks when the provider
+// blocks are regenerated.
+func desiredProviderKeys(providers []hcltmpl.NamedBlock) []string {
+	keys := make([]string, 0, len(providers))
+	for _, p := range providers {
+		if alias := providerAlias(p); alias != "" {
+			keys = append(keys, providerAliasKey(p.Name, alias))
+		} else {
+			keys = append(keys, p.Name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// providerBlockKey returns the provider instance key for an existing
+// `provider "name" { alias = "..." ... }` block parsed from the root
+// module, matching the key format used by desiredProviderKeys.
+func providerBlockKey(block *hclwrite.Block) string {
+	labels := block.Labels()
+	if len(labels) != 1 {
+		return ""
+	}
+	if alias, ok := attributeStringValue(block.Body().GetAttribute("alias")); ok && alias != "" {
+		return providerAliasKey(labels[0], alias)
+	}
+	return labels[0]
+}
+
+// upgradeVariablesTF appends variable declarations for any provider
+// instance in input that doesn't already have one in variables.tf, and
+// removes declarations for provider instances named in
+// previousProviderVars that no longer exist, preserving any other
+// variables already declared there (e.g. ones an operator added for their
+// module). previousProviderVars comes from upgradeProvidersTFVars, which
+// is the authoritative record of which variables were actually generated
+// for providers, so only those are eligible for removal. It returns the
+// names of the variables that were added and removed.
+func upgradeVariablesTF(input *RootModuleInputData, previousProviderVars []string) ([]string, []string, error) {
+	path := filepath.Join(input.Path, VarsFilename)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// variables.tf doesn't exist yet; InitRootModule is responsible
+			// for creating it for a brand new root module.
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("error reading existing %s for task %q: %s", VarsFilename, input.Task.Name, err)
+	}
+
+	f, diags := hclwrite.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("error parsing existing %s for task %q: %s", VarsFilename, input.Task.Name, diags)
+	}
+
+	wantNames := make(map[string]bool, len(input.Providers))
+	for _, p := range input.Providers {
+		wantNames[providerVarName(p)] = true
+	}
+
+	declared := make(map[string]*hclwrite.Block)
+	for _, block := range f.Body().Blocks() {
+		if block.Type() == "variable" && len(block.Labels()) == 1 {
+			declared[block.Labels()[0]] = block
+		}
+	}
+
+	var removed []string
+	for _, name := range previousProviderVars {
+		if block, ok := declared[name]; ok && !wantNames[name] {
+			f.Body().RemoveBlock(block)
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	var added []string
+	for name := range wantNames {
+		if _, ok := declared[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+
+	if len(added) == 0 && len(removed) == 0 {
+		return nil, nil, nil
+	}
+
+	for _, name := range added {
+		f.Body().AppendNewline()
+		varBody := f.Body().AppendNewBlock("variable", []string{name}).Body()
+		varBody.SetAttributeRaw("type", typeExprTokens("any"))
+	}
+
+	content := hclwrite.Format(f.Bytes())
+	if err := os.WriteFile(path, content, input.FilePerms); err != nil {
+		return nil, nil, fmt.Errorf("error upgrading %s for task %q: %s", VarsFilename, input.Task.Name, err)
+	}
+
+	return added, removed, nil
+}
+
+// tokensEqual reports whether two hclwrite token streams render to the
+// same formatted source, so callers can tell whether a block needs to be
+// regenerated without depending on exact whitespace.
+func tokensEqual(a, b hclwrite.Tokens) bool {
+	return bytes.Equal(hclwrite.Format(tokensBytes(a)), hclwrite.Format(tokensBytes(b)))
+}
+
+func tokensBytes(tokens hclwrite.Tokens) []byte {
+	var buf bytes.Buffer
+	for _, tok := range tokens {
+		buf.Write(tok.Bytes)
+	}
+	return buf.Bytes()
+}
+
+// attributeEqualsString reports whether an hclwrite attribute's expression
+// is the HCL string literal equivalent of want.
+func attributeEqualsString(attr *hclwrite.Attribute, want string) bool {
+	if attr == nil {
+		return false
+	}
+	var b strings.Builder
+	for _, tok := range attr.Expr().BuildTokens(nil) {
+		b.Write(tok.Bytes)
+	}
+	return strings.TrimSpace(b.String()) == fmt.Sprintf("%q", want)
+}
+
+// attributeStringValue returns an hclwrite attribute's value when its
+// expression is a plain quoted string literal, e.g. `alias = "west"`. It
+// reports false for anything else (a reference, a more complex
+// expression, or a nil attribute), since those can't be recovered without
+// evaluating the expression.
+func attributeStringValue(attr *hclwrite.Attribute) (string, bool) {
+	if attr == nil {
+		return "", false
+	}
+	var b strings.Builder
+	for _, tok := range attr.Expr().BuildTokens(nil) {
+		b.Write(tok.Bytes)
+	}
+	unquoted, err := strconv.Unquote(strings.TrimSpace(b.String()))
+	if err != nil {
+		return "", false
+	}
+	return unquoted, true
+}
+
 // InitRootModule generates the root module and writes the following files to
 // disk.
-//   always: main.tf, variables.tf, terraform.tfvars.tmpl
+//
+//	always: main.tf, variables.tf, terraform.tfvars.tmpl
+//
 // conditionally: variables.module.tf, providers.tfvars
+//
+// For upgrading a root module that already exists on disk, see
+// UpgradeRootModule, which preserves operator edits instead of
+// unconditionally overwriting files.
 func InitRootModule(input *RootModuleInputData) error {
 	input.init()
 
@@ -252,12 +780,17 @@ func newMainTF(w io.Writer, filename string, input *RootModuleInputData) error {
 	hclFile := hclwrite.NewEmptyFile()
 	rootBody := hclFile.Body()
 	rootBody.AppendNewline()
-	appendRootTerraformBlock(rootBody, input.backend, input.ProviderInfo)
+	if diags := appendRootTerraformBlock(rootBody, input.backend, input.ProviderRequirements); diags.HasErrors() {
+		return fmt.Errorf("error generating required_providers for task %q: %s",
+			input.Task.Name, diags)
+	}
 	rootBody.AppendNewline()
 	appendRootProviderBlocks(rootBody, input.Providers)
 	rootBody.AppendNewline()
-	appendRootModuleBlock(rootBody, input.Task, input.Condition,
-		input.Variables.Keys())
+	if err := appendRootModuleBlock(rootBody, input.Task, input.Condition,
+		input.Providers, input.Variables.Keys()); err != nil {
+		return err
+	}
 
 	// Format the file before writing
 	content := hclFile.Bytes()
@@ -266,59 +799,93 @@ func newMainTF(w io.Writer, filename string, input *RootModuleInputData) error {
 	return err
 }
 
-// appendRootTerraformBlock appends the Terraform block with version constraint
-// and backend.
+// appendRootTerraformBlock appends the Terraform block with version
+// constraint, required provider sources, and backend.
 func appendRootTerraformBlock(body *hclwrite.Body, backend *hcltmpl.NamedBlock,
-	providerInfo map[string]interface{}) {
+	providerRequirements []ProviderRequirement) hcl.Diagnostics {
 
 	tfBlock := body.AppendNewBlock("terraform", nil)
 	tfBody := tfBlock.Body()
 	tfBody.SetAttributeValue("required_version", cty.StringVal(TerraformRequiredVersion))
 
-	if len(providerInfo) != 0 {
+	if len(providerRequirements) != 0 {
 		requiredProvidersBody := tfBody.AppendNewBlock("required_providers", nil).Body()
-		for _, pName := range sortedKeys(providerInfo) {
-			info, ok := providerInfo[pName]
-			if ok {
-				requiredProvidersBody.SetAttributeValue(pName, hcl2shim.HCL2ValueFromConfigValue(info))
-			}
+		if diags := appendRequiredProviders(requiredProvidersBody, providerRequirements); diags.HasErrors() {
+			return diags
 		}
 	}
 
 	// Configure the Terraform backend within the Terraform block
 	if backend == nil || backend.Name == "" {
-		return
+		return nil
 	}
-	backendBody := tfBody.AppendNewBlock("backend", []string{backend.Name}).Body()
+	backendBody := tfBlock.Body().AppendNewBlock("backend", []string{backend.Name}).Body()
 	backendAttrs := backend.SortedAttributes()
 	for _, attr := range backendAttrs {
 		backendBody.SetAttributeValue(attr, backend.Variables[attr])
 	}
+	return nil
 }
 
-// appendRootProviderBlocks appends Terraform provider blocks for the providers
-// the task requires.
+// appendRequiredProviders writes required_providers entries using the
+// Terraform 0.13+ structured source/version form, defaulting the source to
+// "hashicorp/<name>" when unspecified. It errors when the same local
+// provider name is required with conflicting source addresses.
+func appendRequiredProviders(body *hclwrite.Body, requirements []ProviderRequirement) hcl.Diagnostics {
+	seenSources := make(map[string]string)
+	for _, req := range requirements {
+		source := req.Source
+		if source == "" {
+			source = fmt.Sprintf("hashicorp/%s", req.Name)
+		}
+
+		if existing, ok := seenSources[req.Name]; ok && existing != source {
+			return hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "conflicting provider source addresses",
+				Detail: fmt.Sprintf("provider %q is required with conflicting source "+
+					"addresses %q and %q", req.Name, existing, source),
+			}}
+		}
+		seenSources[req.Name] = source
+
+		entry := map[string]cty.Value{
+			"source": cty.StringVal(source),
+		}
+		if req.Version != "" {
+			entry["version"] = cty.StringVal(req.Version)
+		}
+		body.SetAttributeValue(req.Name, cty.ObjectVal(entry))
+	}
+	return nil
+}
+
+// appendRootProviderBlocks appends Terraform provider blocks for the
+// providers the task requires. A provider instance configured with an
+// alias is written as `provider "name" { alias = "..." ... }`, with its
+// arguments sourced from a distinct `var.<name>_<alias>` variable, so a
+// single task can reference multiple configured instances of one
+// provider (e.g. multi-region AWS).
 func appendRootProviderBlocks(body *hclwrite.Body, providers []hcltmpl.NamedBlock) {
 	lastIdx := len(providers) - 1
 	for i, p := range providers {
 		providerBody := body.AppendNewBlock("provider", []string{p.Name}).Body()
 
+		if alias := providerAlias(p); alias != "" {
+			providerBody.SetAttributeValue("alias", cty.StringVal(alias))
+		}
+		varName := providerVarName(p)
+
 		// Convert user provider config to provider block arguments from variables
 		// and sort the attributes / sub-attributes for consistency. Format
 		// depends on if attribute is type object or not:
-		// attr = var.<providerName>.<attr>
+		// attr = var.<varName>.<attr>
 		// objAttr {
-		//    subAttr = var.<providerName>.<objAttr>.<subAttr>
+		//    subAttr = var.<varName>.<objAttr>.<subAttr>
 		// }
 		providerAttrs := p.SortedAttributes()
 		for _, attr := range providerAttrs {
-			// Drop the alias meta attribute. Each provider instance will be ran as
-			// a separate task
-			if attr == "alias" {
-				continue
-			}
-			// auto_commit is an internal setting
-			if attr == "auto_commit" {
+			if providerMetaAttributes[attr] {
 				continue
 			}
 
@@ -333,7 +900,7 @@ func appendRootProviderBlocks(body *hclwrite.Body, providers []hcltmpl.NamedBloc
 				for _, subAttr := range sortedKeys(subAttrs) {
 					objProviderBody.SetAttributeTraversal(subAttr, hcl.Traversal{
 						hcl.TraverseRoot{Name: "var"},
-						hcl.TraverseAttr{Name: p.Name},
+						hcl.TraverseAttr{Name: varName},
 						hcl.TraverseAttr{Name: attr},
 						hcl.TraverseAttr{Name: subAttr},
 					})
@@ -343,7 +910,7 @@ func appendRootProviderBlocks(body *hclwrite.Body, providers []hcltmpl.NamedBloc
 
 			providerBody.SetAttributeTraversal(attr, hcl.Traversal{
 				hcl.TraverseRoot{Name: "var"},
-				hcl.TraverseAttr{Name: p.Name},
+				hcl.TraverseAttr{Name: varName},
 				hcl.TraverseAttr{Name: attr},
 			})
 		}
@@ -353,15 +920,55 @@ func appendRootProviderBlocks(body *hclwrite.Body, providers []hcltmpl.NamedBloc
 	}
 }
 
-// appendRootModuleBlock appends a Terraform module block for the task
+// providerMetaAttributes are NamedBlock attributes that configure a
+// provider instance's generation rather than becoming part of its
+// provider variable: "alias" is written directly on the provider block as
+// a meta-argument, and "auto_commit" is an internal setting. Both
+// appendRootProviderBlocks and newProvidersTFVars skip these so the
+// provider block's var.<name> references and the variable's object value
+// agree on shape.
+var providerMetaAttributes = map[string]bool{
+	"alias":       true,
+	"auto_commit": true,
+}
+
+// providerAlias returns the provider instance's configured alias, or an
+// empty string for the single-instance (unaliased) case.
+func providerAlias(p hcltmpl.NamedBlock) string {
+	val, ok := p.Variables["alias"]
+	if !ok || val.IsNull() || val.Type() != cty.String {
+		return ""
+	}
+	return val.AsString()
+}
+
+// providerVarName returns the variable name that supplies a provider
+// instance's configuration: var.<name> for the single-instance case, or
+// var.<name>_<alias> for an aliased instance. newVariablesTF and
+// newProvidersTFVars use the same name to declare and populate the
+// variable this provider block references.
+func providerVarName(p hcltmpl.NamedBlock) string {
+	if alias := providerAlias(p); alias != "" {
+		return fmt.Sprintf("%s_%s", p.Name, alias)
+	}
+	return p.Name
+}
+
+// appendRootModuleBlock appends a Terraform module block for the task, or,
+// for a task configured with InlineContent, appends the parsed inline HCL
+// directly to the root module in place of the module block indirection.
 func appendRootModuleBlock(body *hclwrite.Body, task Task, cond Condition,
-	varNames []string) {
+	providers []hcltmpl.NamedBlock, varNames []string) error {
 
 	// Add user description for task above the module block
 	if task.Description != "" {
 		appendComment(body, task.Description)
 	}
 
+	if task.InlineContent != "" {
+		return appendInlineModuleContent(body, task)
+	}
+
 	moduleBlock := body.AppendNewBlock("module", []string{task.Name})
 	moduleBody := moduleBlock.Body()
 
@@ -380,6 +987,12 @@ func appendRootModuleBlock(body *hclwrite.Body, task Task, cond Condition,
 		cond.appendModuleAttribute(moduleBody)
 	}
 
+	if len(task.ConfigurationAliases) != 0 {
+		if err := appendModuleProvidersAttribute(moduleBody, task.ConfigurationAliases, providers); err != nil {
+			return err
+		}
+	}
+
 	if len(varNames) != 0 {
 		moduleBody.AppendNewline()
 	}
@@ -389,6 +1002,118 @@ func appendRootModuleBlock(body *hclwrite.Body, task Task, cond Condition,
 			hcl.TraverseAttr{Name: name},
 		})
 	}
+	return nil
+}
+
+// appendModuleProvidersAttribute appends a `providers` argument to a module
+// block, mapping each of the task's aliased provider instances (e.g.
+// "aws.west") to itself, so a module that declares configuration_aliases
+// receives the correct provider instance. Each entry in aliases must
+// correspond to a provider instance actually generated from providers; a
+// stale or mismatched configuration_aliases entry is rejected rather than
+// silently emitting a dangling reference.
+func appendModuleProvidersAttribute(moduleBody *hclwrite.Body, aliases []string, providers []hcltmpl.NamedBlock) error {
+	generated := make(map[string]bool)
+	for _, p := range providers {
+		if alias := providerAlias(p); alias != "" {
+			generated[providerAliasKey(p.Name, alias)] = true
+		}
+	}
+
+	sorted := append([]string(nil), aliases...)
+	sort.Strings(sorted)
+
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrace, Bytes: []byte("{")},
+		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	}
+	for _, alias := range sorted {
+		if !generated[alias] {
+			return fmt.Errorf("configuration_aliases entry %q does not match any "+
+				"generated aliased provider instance", alias)
+		}
+
+		name, aliasName, err := splitProviderAliasKey(alias)
+		if err != nil {
+			return err
+		}
+
+		ref := providerAliasTraversalTokens(name, aliasName)
+		tokens = append(tokens, ref...)
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte(" = ")})
+		tokens = append(tokens, ref...)
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+	}
+	tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte("}")})
+
+	moduleBody.SetAttributeRaw("providers", tokens)
+	return nil
+}
+
+// providerAliasKey returns the "name.alias" key used to identify an
+// aliased provider instance, matching the configuration_aliases entry
+// syntax.
+func providerAliasKey(name, alias string) string {
+	return fmt.Sprintf("%s.%s", name, alias)
+}
+
+// splitProviderAliasKey splits a "name.alias" configuration_aliases entry
+// into its provider name and alias.
+func splitProviderAliasKey(key string) (string, string, error) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf(`invalid configuration_aliases entry %q: expected "name.alias"`, key)
+	}
+	return parts[0], parts[1], nil
+}
+
+// providerAliasTraversalTokens returns the HCL traversal tokens for a
+// provider.alias reference (e.g. aws.west), used as both the key and value
+// of a module block's providers map.
+func providerAliasTraversalTokens(name, alias string) hclwrite.Tokens {
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(name)},
+		{Type: hclsyntax.TokenDot, Bytes: []byte(".")},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(alias)},
+	}
+}
+
+// appendInlineModuleContent parses the task's inline HCL content and
+// appends it to the root module body verbatim. Parsing with hclsyntax
+// ensures the operator-supplied snippet is valid HCL before it is written
+// to disk, so errors surface at task configuration time rather than at
+// `terraform init`.
+func appendInlineModuleContent(body *hclwrite.Body, task Task) error {
+	filename := fmt.Sprintf("%s_inline.tf", task.Name)
+
+	inlineFile, diags := hclwrite.ParseConfig([]byte(task.InlineContent), filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("error parsing inline content for task %q: %s", task.Name, diags)
+	}
+
+	tokens := inlineFile.Body().BuildTokens(nil)
+	if !tokensReferenceServicesVariable(tokens) {
+		log.Printf("[WARN] (templates.tftmpl) inline content for task %q does not "+
+			"reference var.services; the generated Consul service data will be unused",
+			task.Name)
+	}
+
+	body.AppendUnstructuredTokens(tokens)
+	return nil
+}
+
+// tokensReferenceServicesVariable reports whether the token stream contains
+// a var.services reference, so CTS can warn operators whose inline content
+// has no use for the generated service data.
+func tokensReferenceServicesVariable(tokens hclwrite.Tokens) bool {
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i].Type == hclsyntax.TokenIdent && string(tokens[i].Bytes) == "var" &&
+			tokens[i+1].Type == hclsyntax.TokenDot &&
+			tokens[i+2].Type == hclsyntax.TokenIdent && string(tokens[i+2].Bytes) == "services" {
+			return true
+		}
+	}
+	return false
 }
 
 // appendComment appends a single HCL comment line
@@ -400,6 +1125,133 @@ func appendComment(b *hclwrite.Body, comment string) {
 	b.AppendNewline()
 }
 
+// ProviderConstraint is a required_providers entry parsed from a generated
+// root module file, used to validate the task's provider requirements
+// against the installed Terraform binary and provider source addresses.
+type ProviderConstraint struct {
+	Name    string
+	Source  string
+	Version string
+	Range   hcl.Range
+}
+
+// providerSourceRe matches a provider source address in either the legacy
+// two-part "namespace/name" form or the fully qualified
+// "hostname/namespace/name" form.
+var providerSourceRe = regexp.MustCompile(
+	`^([A-Za-z0-9][A-Za-z0-9-]*(\.[A-Za-z0-9][A-Za-z0-9-]*)*/)?[A-Za-z0-9][A-Za-z0-9-]*/[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+// ValidateProviderSource checks that a provider source address parses as a
+// valid legacy-or-new-style address, so that a task with a malformed
+// source fails fast at startup instead of deep inside `terraform init`.
+func ValidateProviderSource(source string) error {
+	if !providerSourceRe.MatchString(source) {
+		return fmt.Errorf("invalid provider source address %q: expected "+
+			`"namespace/name" or "hostname/namespace/name"`, source)
+	}
+	return nil
+}
+
+// ParseRequiredVersion reads the terraform block's required_version
+// constraint out of a generated root module file, returning the
+// constraint string and its source range for diagnostics. It returns an
+// empty constraint and no error if the file has no required_version set.
+func ParseRequiredVersion(path string) (string, hcl.Range, hcl.Diagnostics) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", hcl.Range{}, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "unable to read root module file",
+			Detail:   err.Error(),
+		}}
+	}
+
+	f, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", hcl.Range{}, diags
+	}
+
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return "", hcl.Range{}, nil
+	}
+
+	for _, block := range body.Blocks {
+		if block.Type != "terraform" {
+			continue
+		}
+		attr, ok := block.Body.Attributes["required_version"]
+		if !ok {
+			continue
+		}
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return "", attr.SrcRange, diags
+		}
+		return val.AsString(), attr.SrcRange, nil
+	}
+
+	return "", hcl.Range{}, nil
+}
+
+// ParseRequiredProviders reads the required_providers entries from a
+// generated root module file, returning each provider's source address and
+// version constraint along with its source range for diagnostics.
+func ParseRequiredProviders(path string) (map[string]ProviderConstraint, hcl.Diagnostics) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "unable to read root module file",
+			Detail:   err.Error(),
+		}}
+	}
+
+	f, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, nil
+	}
+
+	constraints := make(map[string]ProviderConstraint)
+	for _, block := range body.Blocks {
+		if block.Type != "terraform" {
+			continue
+		}
+		for _, inner := range block.Body.Blocks {
+			if inner.Type != "required_providers" {
+				continue
+			}
+			for name, attr := range inner.Body.Attributes {
+				val, diags := attr.Expr.Value(nil)
+				if diags.HasErrors() {
+					return nil, diags
+				}
+
+				pc := ProviderConstraint{Name: name, Range: attr.SrcRange}
+				if val.Type().IsObjectType() {
+					m := val.AsValueMap()
+					if s, ok := m["source"]; ok {
+						pc.Source = s.AsString()
+					}
+					if v, ok := m["version"]; ok {
+						pc.Version = v.AsString()
+					}
+				} else if val.Type() == cty.String {
+					pc.Version = val.AsString()
+				}
+				constraints[name] = pc
+			}
+		}
+	}
+
+	return constraints, nil
+}
+
 func fileExists(name string) bool {
 	if _, err := os.Stat(name); err != nil {
 		if os.IsNotExist(err) {
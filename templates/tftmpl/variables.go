@@ -0,0 +1,74 @@
+package tftmpl
+
+import (
+	"io"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// newVariablesTF writes content used for variables.tf of a Terraform root
+// module: the required services variable and one variable per configured
+// provider instance. An aliased provider instance (see providerVarName)
+// gets its own distinct variable declaration, so a task referencing
+// multiple instances of one provider has a place to supply each instance's
+// configuration separately.
+func newVariablesTF(w io.Writer, filename string, input *RootModuleInputData) error {
+	if err := writePreamble(w, input.Task, filename); err != nil {
+		return err
+	}
+
+	hclFile := hclwrite.NewEmptyFile()
+	rootBody := hclFile.Body()
+	rootBody.AppendNewline()
+
+	servicesBody := rootBody.AppendNewBlock("variable", []string{"services"}).Body()
+	servicesBody.SetAttributeRaw("type", typeExprTokens("any"))
+
+	for _, p := range input.Providers {
+		rootBody.AppendNewline()
+		varBody := rootBody.AppendNewBlock("variable", []string{providerVarName(p)}).Body()
+		varBody.SetAttributeRaw("type", typeExprTokens("any"))
+	}
+
+	content := hclwrite.Format(hclFile.Bytes())
+	_, err := w.Write(content)
+	return err
+}
+
+// newProvidersTFVars writes content used for providers.tfvars, assigning
+// each provider instance's configured values to the variable declared for
+// it in variables.tf (see providerVarName).
+func newProvidersTFVars(w io.Writer, filename string, input *RootModuleInputData) error {
+	if err := writePreamble(w, input.Task, filename); err != nil {
+		return err
+	}
+
+	hclFile := hclwrite.NewEmptyFile()
+	rootBody := hclFile.Body()
+	rootBody.AppendNewline()
+
+	for _, p := range input.Providers {
+		attrs := make(map[string]cty.Value)
+		for _, attr := range p.SortedAttributes() {
+			if providerMetaAttributes[attr] {
+				continue
+			}
+			attrs[attr] = p.Variables[attr]
+		}
+		rootBody.SetAttributeValue(providerVarName(p), cty.ObjectVal(attrs))
+	}
+
+	content := hclwrite.Format(hclFile.Bytes())
+	_, err := w.Write(content)
+	return err
+}
+
+// typeExprTokens returns the token representation of a bare type
+// expression (e.g. `any`) for use as a variable's `type` attribute.
+func typeExprTokens(typeExpr string) hclwrite.Tokens {
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(typeExpr)},
+	}
+}